@@ -8,13 +8,16 @@
 package tdbank
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/araddon/dateparse"
-	"github.com/sclevine/agouti"
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,17 +25,7 @@ const (
 	DefaultLoginUrl        = "https://onlinebanking.tdbank.com/"
 	AccountBalanceSelector = "table[id=Table2] span, table[id=AccountBalanceSection] span"
 	AccountHistorySelector = "table.td-table.td-table-stripe-row.td-table-hover-row.td-table-border-column tbody"
-)
-
-var (
-	DefaultHandlers = map[string]func(*HistoryRecord, string) error{
-		"Date":            (*HistoryRecord).DateFromString,
-		"Type":            (*HistoryRecord).TypeFromString,
-		"Description":     (*HistoryRecord).DescriptionFromString,
-		"Debit":           (*HistoryRecord).DebitFromString,
-		"Credit":          (*HistoryRecord).CreditFromString,
-		"Account Balance": (*HistoryRecord).BalanceFromString,
-	}
+	AccountsSelector       = "table[id=AccountSummary] tbody tr"
 )
 
 // A HistoryRecord contains one line from an account history.
@@ -40,7 +33,9 @@ var (
 // credit cards) and debit accounts (e.g., checking accounts).
 // Other methods in this package make reasonable efforts to fill
 // in missing fields -- for example, by computing a running balance
-// if the account history doesn't show one.
+// if the account history doesn't show one. Columns that don't map
+// to a known field (see HandlerRegistry) are stashed in Extra instead
+// of being silently dropped.
 type HistoryRecord struct {
 	Index       int
 	Date        time.Time
@@ -49,15 +44,148 @@ type HistoryRecord struct {
 	Debit       int64
 	Credit      int64
 	Balance     int64
+	Extra       map[string]string
+}
+
+// A HandlerRegistry maps account-history column names to the
+// HistoryRecord field they populate. TD Bank shows different columns
+// for different account types -- "Posting Date" and "Category" for
+// credit cards, plain "Date" for checking -- so the registry is a
+// mutable, per-Client structure rather than a fixed package-level
+// table: callers can Register a handler for a column this package
+// doesn't already know about, or Alias one column name onto another.
+type HandlerRegistry struct {
+	handlers map[string]func(*HistoryRecord, string) error
+	aliases  map[string]string
+}
+
+// NewHandlerRegistry returns a HandlerRegistry pre-populated with
+// handlers for the column names TD Bank is known to use.
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers: map[string]func(*HistoryRecord, string) error{
+			"Date":            (*HistoryRecord).DateFromString,
+			"Type":            (*HistoryRecord).TypeFromString,
+			"Description":     (*HistoryRecord).DescriptionFromString,
+			"Debit":           (*HistoryRecord).DebitFromString,
+			"Credit":          (*HistoryRecord).CreditFromString,
+			"Account Balance": (*HistoryRecord).BalanceFromString,
+		},
+		aliases: map[string]string{},
+	}
+}
+
+// Register associates fieldName with fn, so that a column with that
+// name is parsed by fn when ParseAccountHistory encounters it. Use
+// this to support column names this package doesn't already handle,
+// without patching the library.
+func (registry *HandlerRegistry) Register(fieldName string, fn func(*HistoryRecord, string) error) {
+	registry.handlers[fieldName] = fn
+}
+
+// Alias makes fieldName resolve to the same handler as canonicalName.
+// For example, a credit-card statement's "Posting Date" column can be
+// aliased onto "Date" so it's parsed the same way:
+//
+//	registry.Alias("Posting Date", "Date")
+func (registry *HandlerRegistry) Alias(fieldName, canonicalName string) {
+	registry.aliases[fieldName] = canonicalName
+}
+
+// lookup returns the handler registered for fieldName, resolving one
+// level of alias if necessary.
+func (registry *HandlerRegistry) lookup(fieldName string) (func(*HistoryRecord, string) error, bool) {
+	if fn, found := registry.handlers[fieldName]; found {
+		return fn, true
+	}
+	if canonical, found := registry.aliases[fieldName]; found {
+		fn, found := registry.handlers[canonical]
+		return fn, found
+	}
+	return nil, false
+}
+
+// Hash returns a stable content hash for the record, derived from
+// its Date, Type, Description, Debit, Credit, and Index. Two records
+// scraped from overlapping date ranges hash identically, so the Store
+// interface can use it as a primary key to de-duplicate re-scraped
+// history.
+func (record HistoryRecord) Hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%d|%d|%d",
+		record.Date.Format(time.RFC3339), record.Type, record.Description,
+		record.Debit, record.Credit, record.Index)
+	return hex.EncodeToString(h.Sum(nil))
 }
 
-// A Client represents a virtual web browser. It holds pointers
-// to the Chrome web driver and the current page. Most functions
-// in this package are implemented as methods of client, because
-// they always need a web browser.
+// A Store persists HistoryRecords and allows them to be queried back
+// by account and date range. Upsert is keyed on HistoryRecord.Hash,
+// so calling it with records that overlap ones already stored is
+// safe: it reports how many were newly added versus already present.
+// Implementations (SQLite, PostgreSQL, a flat JSON file) live in the
+// tdbank/store package; Store is declared here, rather than there,
+// so that Client.SyncAccount can accept one without an import cycle.
+type Store interface {
+	Upsert(account string, records []HistoryRecord) (added, skipped int, err error)
+	Latest(account string) (time.Time, error)
+	Range(account string, from, to time.Time) ([]HistoryRecord, error)
+}
+
+// A Client represents a virtual web browser. It holds the Driver that
+// talks to it. Most functions in this package are implemented as
+// methods of client, because they always need a web browser.
 type Client struct {
-	driver *agouti.WebDriver
-	page   *agouti.Page
+	// drv is the active Driver, created from factory by Start.
+	drv     Driver
+	factory DriverFactory
+
+	// Handlers maps account-history column names to the
+	// HistoryRecord field they populate. It's created with the
+	// default TD Bank column names the first time it's needed; set
+	// it explicitly (or call Register/Alias on it) before scraping
+	// to customize how columns are parsed.
+	Handlers *HandlerRegistry
+
+	// Retry controls how browser interactions are retried when they
+	// fail transiently. The zero value means DefaultRetryPolicy.
+	Retry RetryPolicy
+}
+
+// An Option configures a Client constructed by NewClient.
+type Option func(*Client)
+
+// WithDriverFactory sets the DriverFactory a Client uses to start its
+// browser. The default, AgoutiDriverFactory, launches agouti's
+// ChromeDriver exactly as a zero-value Client always has; pass
+// ChromedpDriverFactory or PlaywrightDriverFactory to use a backend
+// that doesn't need a chromedriver binary on PATH.
+func WithDriverFactory(factory DriverFactory) Option {
+	return func(client *Client) { client.factory = factory }
+}
+
+// WithRetryPolicy sets the RetryPolicy a Client uses for retrying
+// transient browser failures. The default is DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(client *Client) { client.Retry = policy }
+}
+
+// WithHandlers sets the HandlerRegistry a Client uses to parse
+// account-history columns. The default is NewHandlerRegistry().
+func WithHandlers(registry *HandlerRegistry) Option {
+	return func(client *Client) { client.Handlers = registry }
+}
+
+// NewClient constructs a Client, applying opts in order. It's the
+// preferred way to set a non-default DriverFactory, RetryPolicy, or
+// HandlerRegistry; a zero-value Client (var client tdbank.Client)
+// still works exactly as before, defaulting everything the same way
+// NewClient(), with no options, does.
+func NewClient(opts ...Option) *Client {
+	client := &Client{}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
 }
 
 // An Auth holds the URL of the login page, a username and password,
@@ -70,34 +198,39 @@ type Auth struct {
 	SecurityQuestions map[string]string
 }
 
-// Start launches a virtual browser -- i.e., it initializes a Chrome
-// web driver and launches Chrome with a blank page. The work is done
-// by agouti, which in turn runs chromedriver, so you may want to set
-// up chromedriver to your liking. For example you might want to put
-// a wrapper (named chromedriver) on your path that launches Chrome in
-// headless mode.
-func (client *Client) Start() {
+// Start launches a virtual browser, using client.factory (or
+// AgoutiDriverFactory, by default, which in turn runs chromedriver --
+// so you may want to set up chromedriver to your liking, for example
+// by putting a wrapper named chromedriver on your path that launches
+// Chrome in headless mode). Starting the driver is retried according
+// to client.Retry, since it's prone to transient failures on a loaded
+// machine.
+func (client *Client) Start() error {
 	// Ignore repeated attempts to start the driver
-	if client.driver != nil {
-		return
+	if client.drv != nil {
+		return nil
 	}
 
-	client.driver = agouti.ChromeDriver()
-
-	// Start the driver
-	if err := client.driver.Start(); err != nil {
-		log.Fatalf("Failed to start ChromeDriver: %v", err)
+	factory := client.factory
+	if factory == nil {
+		factory = AgoutiDriverFactory
 	}
 
-	// Make a new page object
-
-	if page, err := client.driver.NewPage(); err != nil {
-		log.Fatalf("Failed to initialize browser: %v", err)
-	} else {
-		client.page = page
+	var drv Driver
+	err := client.retryPolicy().retry(context.Background(), func(ctx context.Context) error {
+		d, err := factory()
+		if err != nil {
+			return err
+		}
+		drv = d
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("starting browser driver: %w", err)
 	}
+	client.drv = drv
 
-	return
+	return nil
 }
 
 // Stop shuts down the web driver and the Chrome process. You
@@ -110,15 +243,15 @@ func (client *Client) Start() {
 //    client.Start()
 //    defer client.Stop()
 func (client *Client) Stop() {
-	if client.driver == nil {
+	if client.drv == nil {
 		log.Print("Ignoring attempt to stop driver that was never started")
 		return
 	}
 
-	if err := client.driver.Stop(); err != nil {
-		log.Fatalf("Failed to stop ChromeDriver: %v", err)
+	if err := client.drv.Close(); err != nil {
+		log.Printf("Failed to stop browser driver: %v", err)
 	}
-	client.driver = nil
+	client.drv = nil
 
 	return
 }
@@ -126,163 +259,323 @@ func (client *Client) Stop() {
 // Login connects to the TD Bank login page and logs in with
 // the supplied username and password. If it notices any of
 // the security questions it was given, then it supplies the
-// answer. When this method returns, the browser should be
-// at the main accounts page.
-func (client *Client) Login(auth Auth) {
-	var loginUrl string
+// answer. When this method returns without error, the browser
+// should be at the main accounts page. It's equivalent to
+// LoginContext with context.Background().
+func (client *Client) Login(auth Auth) error {
+	return client.LoginContext(context.Background(), auth)
+}
 
-	// Use the supplied login URL or the default
-	if loginUrl = auth.LoginUrl; loginUrl == "" {
+// LoginContext behaves like Login, but aborts early -- returning
+// ctx.Err(), or a typed error wrapping it -- if ctx is cancelled or
+// times out before the login flow completes.
+func (client *Client) LoginContext(ctx context.Context, auth Auth) error {
+	loginUrl := auth.LoginUrl
+	if loginUrl == "" {
 		loginUrl = DefaultLoginUrl
 	}
 
-	log.Printf("Going to URL: %s", loginUrl)
-
 	// Start the web driver (using Chrome)
-	client.Start()
-
-	if client.page == nil {
-		log.Fatalf("Page is nil")
+	if err := client.Start(); err != nil {
+		return err
 	}
 
 	// Open the main page and switch to the main frame
-	err := client.page.Navigate(loginUrl)
+	policy := client.retryPolicy()
+	err := policy.retry(ctx, func(ctx context.Context) error {
+		return client.drv.Navigate(ctx, loginUrl)
+	})
 	if err != nil {
-		log.Fatalf("Failed to open login page %s: %v", loginUrl, err)
+		return &ErrNavigation{URL: loginUrl, Err: err}
 	}
-	client.page.Find("frame[name=main]").SwitchToFrame()
+	client.drv.SwitchFrame(ctx, "frame[name=main]")
 
 	// Log in
-	selection := client.page.FindByID("txtUser")
-	if err = selection.Fill(auth.Username); err != nil {
-		log.Printf("Failed to enter username: %v", err)
+	if err := client.drv.Fill(ctx, "#txtUser", auth.Username); err != nil {
+		return &ErrLoginFailed{Reason: "entering username", Err: err}
 	}
 
-	selection = client.page.FindByID("txtPassword")
-	if err = selection.Fill(auth.Password); err != nil {
-		log.Printf("Failed to enter password: %v", err)
+	if err := client.drv.Fill(ctx, "#txtPassword", auth.Password); err != nil {
+		return &ErrLoginFailed{Reason: "entering password", Err: err}
 	}
 
-	if err = selection.SendKeys("\n"); err != nil {
-		log.Printf("Failed to type ENTER: %v", err)
+	if err := client.drv.PressEnter(ctx, "#txtPassword"); err != nil {
+		return &ErrLoginFailed{Reason: "submitting credentials", Err: err}
 	}
 
 	// Answer the "questions three," if asked:
-	selection = client.page.All("td[class=question]").At(0)
-	question, err := selection.Text()
-	if err == nil {
-		answers := auth.SecurityQuestions
-		var answer string
-
-		for q, a := range answers {
-			if strings.HasPrefix(question, q) {
-				answer = a
+	if count, _ := client.drv.Count(ctx, "td[class=question]"); count > 0 {
+		if question, err := client.drv.Text(ctx, "td[class=question]"); err == nil {
+			answers := auth.SecurityQuestions
+			var answer string
+
+			for q, a := range answers {
+				if strings.HasPrefix(question, q) {
+					answer = a
+				}
 			}
-		}
 
-		if answer == "" {
-			log.Fatalf("Couldn't answer question: %s", question)
-		}
+			if answer == "" {
+				return &ErrSecurityQuestion{Question: question}
+			}
 
-		selection = client.page.Find("td[class=question] input[name=Newanswer]")
-		if err = selection.Fill(answer); err != nil {
-			log.Fatalf("Failed to answer security question: %v", err)
-		}
+			if err := client.drv.Fill(ctx, "td[class=question] input[name=Newanswer]", answer); err != nil {
+				return &ErrLoginFailed{Reason: "answering security question", Err: err}
+			}
 
-		if err = selection.SendKeys("\n"); err != nil {
-			log.Printf("Failed to type ENTER: %v", err)
+			if err := client.drv.PressEnter(ctx, "td[class=question] input[name=Newanswer]"); err != nil {
+				return &ErrLoginFailed{Reason: "submitting security question", Err: err}
+			}
 		}
 	}
 
 	// Click through the annoying popup. Skip error checking; it might
 	// not be there.
-	selection = client.page.Find("div[id=continue] a")
-	link, _ := selection.Attribute("href")
-	if link != "" {
-		client.page.Navigate(link)
+	if link, _ := client.drv.Attribute(ctx, "div[id=continue] a", "href"); link != "" {
+		client.drv.Navigate(ctx, link)
 	}
 
-	return
+	// The optional steps above ignore their errors, so a cancellation
+	// during them has to be checked for here.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // ViewAccounts takes the browser back to the main accounts page.
 func (client *Client) ViewAccounts() error {
-	// Find the accounts link
-	selection := client.page.FindByLink("Accounts")
-	if count, err := selection.Count(); count == 0 || err != nil {
-		log.Printf("Unable to find link for accounts tab: %v", err)
-		return err
+	if client.drv == nil {
+		return &ErrElementNotFound{Selector: "(no browser driver started)"}
 	}
 
-	if err := selection.Click(); err != nil {
-		log.Printf("Unable to click accounts tab: %v", err)
-		return err
+	if err := client.drv.ClickLink(context.Background(), "Accounts"); err != nil {
+		return &ErrElementNotFound{Selector: "link: Accounts", Err: err}
 	}
 
 	return nil
 }
 
+// An Account describes one account found on the main accounts page:
+// its display name, type, masked account number, and current
+// balance. Name is exactly the string ViewAccountHistory expects as
+// its account argument.
+type Account struct {
+	Name         string
+	Type         string // "checking", "savings", "credit", "loan", or else the lowercased section header
+	MaskedNumber string
+	Balance      int64
+}
+
+// ListAccounts scrapes the main accounts page for every account the
+// logged-in session can see. The browser must already be on the main
+// accounts page; call ViewAccounts first if in doubt. TD Bank groups
+// accounts under section headers ("Checking", "Savings", "Credit
+// Cards", ...); ListAccounts classifies the nearest preceding header
+// as each account's Type.
+func (client *Client) ListAccounts() ([]Account, error) {
+	if client.drv == nil {
+		return nil, &ErrElementNotFound{Selector: "(no browser driver started)"}
+	}
+
+	ctx := context.Background()
+
+	n, err := client.drv.Count(ctx, AccountsSelector)
+	if err != nil {
+		return nil, &ErrElementNotFound{Selector: AccountsSelector, Err: err}
+	}
+
+	var accounts []Account
+	var currentType string
+
+	for i := 1; i <= n; i++ {
+		rowSelector := fmt.Sprintf("%s:nth-child(%d)", AccountsSelector, i)
+
+		name, _ := client.drv.Text(ctx, rowSelector+" a")
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			// A section header row, introducing a new account type.
+			if text, err := client.drv.Text(ctx, rowSelector); err == nil && strings.TrimSpace(text) != "" {
+				currentType = strings.ToLower(strings.TrimSpace(text))
+				for _, kind := range []string{"checking", "savings", "credit", "loan"} {
+					if strings.Contains(currentType, kind) {
+						currentType = kind
+						break
+					}
+				}
+			}
+			continue
+		}
+
+		account := Account{Name: name, Type: currentType}
+
+		cells, _ := client.drv.TextAll(ctx, rowSelector+" td")
+
+		for _, text := range cells {
+			text = strings.TrimSpace(text)
+
+			if text == "" || text == name {
+				continue
+			}
+			if looksLikeMoney(text) {
+				if balance, err := parseMoney(text); err == nil {
+					account.Balance = balance
+					continue
+				}
+			}
+			if account.MaskedNumber == "" {
+				account.MaskedNumber = text
+			}
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+// SyncAll fetches account history for every account in accounts, for
+// the given date range, and returns it keyed by Account.Name. TD Bank
+// only allows one logged-in session per login, so by default the work
+// is done one account at a time within this Client's own session. If
+// already-started and logged-in Clients are passed in others, accounts
+// are instead spread across a worker pool of up to concurrency of
+// them (this Client included), each fetching its own share serially.
+// SyncAll keeps going after an account fails, and returns the first
+// error it saw, if any, alongside whatever it did manage to fetch.
+func (client *Client) SyncAll(accounts []Account, start, end time.Time, concurrency int, others ...*Client) (map[string][]HistoryRecord, error) {
+	clients := append([]*Client{client}, others...)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(clients) {
+		concurrency = len(clients)
+	}
+
+	jobs := make(chan Account, len(accounts))
+	for _, account := range accounts {
+		jobs <- account
+	}
+	close(jobs)
+
+	type outcome struct {
+		name    string
+		records []HistoryRecord
+		err     error
+	}
+	results := make(chan outcome, len(accounts))
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		worker := clients[i]
+		wg.Add(1)
+
+		go func(worker *Client) {
+			defer wg.Done()
+			for account := range jobs {
+				records, err := worker.fetchAccountHistory(account.Name, start, end)
+				results <- outcome{name: account.Name, records: records, err: err}
+			}
+		}(worker)
+	}
+
+	wg.Wait()
+	close(results)
+
+	synced := make(map[string][]HistoryRecord, len(accounts))
+	var firstErr error
+
+	for r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		synced[r.name] = r.records
+	}
+
+	return synced, firstErr
+}
+
+// fetchAccountHistory returns to the main accounts page, then views
+// and parses account's history for the given date range.
+func (client *Client) fetchAccountHistory(account string, start, end time.Time) ([]HistoryRecord, error) {
+	if err := client.ViewAccounts(); err != nil {
+		return nil, err
+	}
+	if err := client.ViewAccountHistory(account, start, end); err != nil {
+		return nil, err
+	}
+	return client.ParseAccountHistory()
+}
+
 // ViewAccountHistory clicks on the provided account name, and
 // then enters the provided start and end dates to view all
 // transactions between those two dates (inclusive). This method
 // assumes that the browser is on the main accounts page already,
-// so if in doubt you should call ViewAccounts first.
+// so if in doubt you should call ViewAccounts first. It's equivalent
+// to ViewAccountHistoryContext with context.Background().
 func (client *Client) ViewAccountHistory(account string, start time.Time, end time.Time) error {
-	// Find the account link
-	selection := client.page.FindByLink(account)
-	if count, err := selection.Count(); count == 0 || err != nil {
-		log.Printf("Unable to find text field for start date: %v", err)
-		return err
+	return client.ViewAccountHistoryContext(context.Background(), account, start, end)
+}
+
+// ViewAccountHistoryContext behaves like ViewAccountHistory, but
+// aborts early -- returning an ErrElementNotFound wrapping ctx.Err()
+// -- if ctx is cancelled or times out before the search completes. Clicking the account link and
+// submitting the search are each retried according to
+// client.Retry, since both are prone to the DOM not being ready yet.
+func (client *Client) ViewAccountHistoryContext(ctx context.Context, account string, start time.Time, end time.Time) error {
+	policy := client.retryPolicy()
+
+	if client.drv == nil {
+		return &ErrElementNotFound{Selector: "(no browser driver started)"}
 	}
 
-	// Click it
-	if err := selection.Click(); err != nil {
-		log.Printf("Failed to click account link for \""+account+"\": %v", err)
-		return err
+	// Click the account link
+	if err := policy.retry(ctx, func(ctx context.Context) error {
+		return client.drv.ClickLink(ctx, account)
+	}); err != nil {
+		return &ErrElementNotFound{Selector: "link: " + account, Err: err}
 	}
 
 	// Find start-date field
-	selection = client.page.FindByID("Text19")
-	if count, err := selection.Count(); count == 0 || err != nil {
-		log.Printf("Unable to find text field for start date: %v", err)
-		return err
+	if count, err := client.drv.Count(ctx, "#Text19"); count == 0 || err != nil {
+		return &ErrElementNotFound{Selector: "#Text19", Err: err}
 	}
 
 	// Enter the start date
-	if err := selection.SendKeys(start.Format("01/02/2006")); err != nil {
-		log.Printf("Failed to set start date: %v", err)
-		return err
+	if err := client.drv.Fill(ctx, "#Text19", start.Format("01/02/2006")); err != nil {
+		return &ErrElementNotFound{Selector: "#Text19", Err: err}
 	}
 	time.Sleep(500 * time.Millisecond)
 
 	// End date field
 	if !end.IsZero() {
 		// Find end-date field
-		selection = client.page.FindByID("Text20")
-		if count, err := selection.Count(); count == 0 || err != nil {
-			log.Printf("Unable to find text field for end date: %v", err)
-			return err
+		if count, err := client.drv.Count(ctx, "#Text20"); count == 0 || err != nil {
+			return &ErrElementNotFound{Selector: "#Text20", Err: err}
 		}
 
 		// Enter the end date
-		if err := selection.SendKeys(end.Format("01/02/2006")); err != nil {
-			log.Printf("Failed to set end date: %v", err)
-			return err
+		if err := client.drv.Fill(ctx, "#Text20", end.Format("01/02/2006")); err != nil {
+			return &ErrElementNotFound{Selector: "#Text20", Err: err}
 		}
 		time.Sleep(500 * time.Millisecond)
 	}
 
 	// Find the search button
-	selection = client.page.FindByID("btnSearch")
-	if count, err := selection.Count(); count == 0 || err != nil {
-		log.Printf("Unable to find search button: %v", err)
-		return err
+	if count, err := client.drv.Count(ctx, "#btnSearch"); count == 0 || err != nil {
+		return &ErrElementNotFound{Selector: "#btnSearch", Err: err}
 	}
 
 	// Clicking the "Show History" button doesn't work in all web drivers
-	if err := selection.SendKeys("\n"); err != nil {
-		log.Printf("Failed to click the Show History button: %v", err)
-		return err
+	if err := policy.retry(ctx, func(ctx context.Context) error {
+		return client.drv.PressEnter(ctx, "#btnSearch")
+	}); err != nil {
+		return &ErrElementNotFound{Selector: "#btnSearch", Err: err}
 	}
 
 	return nil
@@ -293,22 +586,23 @@ func (client *Client) ViewAccountHistory(account string, start time.Time, end ti
 // current page for an account balance. It parses the balance as
 // a 64-bit integer giving the amount in pennies.
 func (client *Client) ParseAccountBalance() (int64, error) {
-	// Find the elements that contain the account balance
-	selections := client.page.All(AccountBalanceSelector)
-
-	var balance int64
-	var err error
+	if client.drv == nil {
+		return 0, &ErrElementNotFound{Selector: "(no browser driver started)"}
+	}
 
-	// Check whether we found it
-	if count, _ := selections.Count(); count == 0 {
+	// Find the elements that contain the account balance
+	texts, err := client.drv.TextAll(context.Background(), AccountBalanceSelector)
+	if err != nil {
+		return 0, &ErrElementNotFound{Selector: AccountBalanceSelector, Err: err}
+	}
+	if len(texts) == 0 {
 		return 0, errors.New("Unable to find account balance in page")
-	} else {
-		value, _ := selections.At(count - 1).Text()
+	}
 
-		// Convert it to an integer
-		if balance, err = parseMoney(value); err != nil {
-			return 0, err
-		}
+	// Convert it to an integer
+	balance, err := parseMoney(texts[len(texts)-1])
+	if err != nil {
+		return 0, err
 	}
 
 	return balance, nil
@@ -321,30 +615,38 @@ func (client *Client) ParseAccountBalance() (int64, error) {
 // balance), then it makes a reasonable effort to calculate them and
 // fill them in anyway.
 func (client *Client) ParseAccountHistory() ([]HistoryRecord, error) {
+	if client.Handlers == nil {
+		client.Handlers = NewHandlerRegistry()
+	}
+
+	if client.drv == nil {
+		return nil, &ErrElementNotFound{Selector: "(no browser driver started)"}
+	}
+
 	var history []HistoryRecord
-	var fieldNames []string
+	ctx := context.Background()
 
 	// Grab the table with the goodies
-	rows := client.page.Find(AccountHistorySelector).All("tr")
-	n, _ := rows.Count()
-
+	n, err := client.drv.Count(ctx, AccountHistorySelector+" tr")
+	if err != nil {
+		return history, &ErrElementNotFound{Selector: AccountHistorySelector, Err: err}
+	}
 	if n == 0 {
 		// There should be at least one row: the header row
 		return history, errors.New("No account history found in page")
 	}
 
-	// Extract the field names
-	header := rows.At(0).All("th, td")
-	m, _ := header.Count()
+	// Extract the field names from the header row
+	fieldNames, err := rowCells(ctx, client.drv, AccountHistorySelector, 1)
+	if err != nil {
+		return history, &ErrElementNotFound{Selector: AccountHistorySelector, Err: err}
+	}
+	m := len(fieldNames)
 
 	var balance int64
 	hasBalance := false
 
-	// Record the field names
-	for i := 0; i < m; i++ {
-		text, _ := header.At(i).Text()
-		fieldNames = append(fieldNames, text)
-
+	for _, text := range fieldNames {
 		if strings.Contains(text, "Balance") {
 			hasBalance = true
 		}
@@ -358,21 +660,27 @@ func (client *Client) ParseAccountHistory() ([]HistoryRecord, error) {
 		}
 	}
 
-	// Iterate through the rows
-	for i := 1; i < n; i++ {
-		row := rows.At(i)
-		cells := row.All("th, td")
+	// Iterate through the data rows (the header is row 1)
+	for i := 2; i <= n; i++ {
+		cells, err := rowCells(ctx, client.drv, AccountHistorySelector, i)
+		if err != nil {
+			log.Printf("Error reading account history row %d: %v", i, err)
+			continue
+		}
 
 		record := HistoryRecord{}
 
 		// Construct a history record using field handlers
-		for j := 0; j < m; j++ {
-			value, _ := cells.At(j).Text()
+		for j := 0; j < m && j < len(cells); j++ {
+			value := cells[j]
 			field := fieldNames[j]
 
-			method, found := DefaultHandlers[field]
+			method, found := client.Handlers.lookup(field)
 			if !found {
-				log.Printf("No handler found for field: %s", field)
+				if record.Extra == nil {
+					record.Extra = map[string]string{}
+				}
+				record.Extra[field] = strings.TrimSpace(value)
 				continue
 			}
 
@@ -411,11 +719,67 @@ func (client *Client) ParseAccountHistory() ([]HistoryRecord, error) {
 	return history, nil
 }
 
+// rowCells returns the text of every header or data cell in the
+// row-th (1-indexed, matching CSS :nth-child) row of the table rooted
+// at tableSelector. Driver has no notion of an element handle, so
+// table scraping works by re-scoping the selector to one row at a
+// time instead of iterating over a cached row selection.
+func rowCells(ctx context.Context, drv Driver, tableSelector string, row int) ([]string, error) {
+	selector := fmt.Sprintf(
+		"%s tr:nth-child(%d) > th, %s tr:nth-child(%d) > td",
+		tableSelector, row, tableSelector, row,
+	)
+	return drv.TextAll(ctx, selector)
+}
+
+// looksLikeMoney reports whether text carries the hallmarks of a
+// dollar amount -- a currency symbol or a decimal point -- rather
+// than a bare number that happens to parse as one, such as a masked
+// account number with no mask characters left in it.
+func looksLikeMoney(text string) bool {
+	return strings.ContainsAny(text, "$.")
+}
+
+// SyncAccount brings a Store up to date with an account's history.
+// It asks the store for the latest date it already has on file,
+// re-scrapes starting lookback before that date (to safely overlap
+// any records added since the last sync), and upserts whatever comes
+// back. The browser must already be on the main accounts page. This
+// turns the client from a one-shot scraper into something you can
+// run on a cron and trust not to duplicate transactions.
+func (client *Client) SyncAccount(account string, store Store, lookback time.Duration) (added, skipped int, err error) {
+	latest, err := store.Latest(account)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := latest.Add(-lookback)
+	if latest.IsZero() {
+		start = time.Time{}
+	}
+
+	if err = client.ViewAccountHistory(account, start, time.Time{}); err != nil {
+		return 0, 0, err
+	}
+
+	records, err := client.ParseAccountHistory()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return store.Upsert(account, records)
+}
+
 // GetHtml returns the HTML for the current web page
 // as a string. This is useful in a pinch for
-// debugging.
+// debugging. Like Client's other methods, it goes through the
+// Driver interface, so it works the same way regardless of which
+// backend is in use.
 func (client *Client) GetHtml() (string, error) {
-	return client.page.HTML()
+	if client.drv == nil {
+		return "", &ErrElementNotFound{Selector: "(no browser driver started)"}
+	}
+	return client.drv.HTML(context.Background())
 }
 
 // PrintHtml prints the HTML for the current web page.
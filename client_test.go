@@ -0,0 +1,83 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryRecordHash(t *testing.T) {
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	a := HistoryRecord{Date: date, Type: "DEBIT", Description: "Coffee", Debit: 500, Index: 1}
+	b := HistoryRecord{Date: date, Type: "DEBIT", Description: "Coffee", Debit: 500, Index: 1}
+	if a.Hash() != b.Hash() {
+		t.Errorf("identical records hashed differently: %q vs %q", a.Hash(), b.Hash())
+	}
+
+	c := a
+	c.Index = 2
+	if a.Hash() == c.Hash() {
+		t.Errorf("records differing only by Index hashed the same: %q", a.Hash())
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"$1,234.56", 123456, false},
+		{"0.00", 0, false},
+		{"42", 42, false},
+		{"not money", 0, true},
+	}
+
+	for _, c := range cases {
+		got, err := parseMoney(c.input)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseMoney(%q) = %d, want error", c.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMoney(%q) returned error: %v", c.input, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseMoney(%q) = %d, want %d", c.input, got, c.want)
+		}
+	}
+}
+
+func TestHandlerRegistryAlias(t *testing.T) {
+	registry := NewHandlerRegistry()
+
+	if _, found := registry.lookup("Posting Date"); found {
+		t.Fatal("Posting Date resolved before it was aliased")
+	}
+
+	registry.Alias("Posting Date", "Date")
+
+	fn, found := registry.lookup("Posting Date")
+	if !found {
+		t.Fatal("Posting Date did not resolve after being aliased to Date")
+	}
+
+	record := &HistoryRecord{}
+	if err := fn(record, "03/15/2024"); err != nil {
+		t.Fatalf("handler for aliased column returned error: %v", err)
+	}
+	if record.Date.IsZero() {
+		t.Error("handler for aliased column did not set Date")
+	}
+
+	if _, found := registry.lookup("Nonexistent Column"); found {
+		t.Error("lookup found a handler for a column that was never registered or aliased")
+	}
+}
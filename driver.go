@@ -0,0 +1,84 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import (
+	"context"
+	"time"
+)
+
+// A Driver abstracts the browser automation engine Client talks to,
+// so that scraping isn't permanently tied to agouti and an external
+// chromedriver binary on PATH. Elements are addressed by CSS selector,
+// since that's the lowest common denominator every backend --
+// agouti, chromedp, Playwright -- supports. Table scraping (over
+// ParseAccountHistory's rows and cells) works by combining Count and
+// TextAll with :nth-child selectors, rather than by handing back
+// element references, so the interface stays selector-only.
+//
+// Every method but Close also takes a context.Context, so that RetryPolicy.Timeout
+// actually bounds a hung call instead of just the delay between
+// retries. chromedp honors it natively; agouti and Playwright have no
+// native cancellation, so their backends race the call against
+// ctx.Done() and return ctx.Err() if it loses, same as a timeout.
+type Driver interface {
+	Navigate(ctx context.Context, url string) error
+	Fill(ctx context.Context, selector, value string) error
+	Click(ctx context.Context, selector string) error
+	Text(ctx context.Context, selector string) (string, error)
+	HTML(ctx context.Context) (string, error)
+	SwitchFrame(ctx context.Context, selector string) error
+	WaitFor(ctx context.Context, selector string, timeout time.Duration) error
+
+	// Count returns the number of elements matching selector.
+	Count(ctx context.Context, selector string) (int, error)
+
+	// TextAll returns the text content of every element matching
+	// selector, in document order.
+	TextAll(ctx context.Context, selector string) ([]string, error)
+
+	// Attribute returns the named attribute of the first element
+	// matching selector.
+	Attribute(ctx context.Context, selector, name string) (string, error)
+
+	// PressEnter submits the form field matching selector by
+	// sending it a carriage return, the way a user hitting Enter
+	// would. TD Bank's search button only reliably responds to this,
+	// not to a plain Click, on some backends.
+	PressEnter(ctx context.Context, selector string) error
+
+	// ClickLink clicks the first anchor whose visible text equals
+	// text. TD Bank's navigation and account lists are identified by
+	// link text rather than a stable selector.
+	ClickLink(ctx context.Context, text string) error
+
+	// Close shuts down the underlying browser and any process or
+	// connection it took to reach it.
+	Close() error
+}
+
+// A DriverFactory creates and starts a new Driver. Client calls it
+// from Start, again on failure as its RetryPolicy allows, to obtain
+// the Driver it uses for the rest of its life. AgoutiDriverFactory is the default, for backward
+// compatibility with code written before Driver existed.
+type DriverFactory func() (Driver, error)
+
+// runCtx runs fn in its own goroutine and returns its error, unless
+// ctx is cancelled or times out first, in which case it returns
+// ctx.Err() without waiting for fn to finish. It's how backends with
+// no native cancellation (agouti, Playwright) honor a Driver method's
+// context: the abandoned goroutine runs to completion in the
+// background, but the caller isn't stuck waiting on it.
+func runCtx(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sclevine/agouti"
+)
+
+// agoutiDriver adapts an agouti WebDriver/Page pair to the Driver
+// interface. It's the backend AgoutiDriverFactory returns, and the
+// one a zero-value Client has always used.
+type agoutiDriver struct {
+	webDriver *agouti.WebDriver
+	page      *agouti.Page
+
+	// mu serializes every call into page. agouti has no way to abort
+	// a call in flight, so a call abandoned by runCtx on timeout
+	// keeps running against page in the background; without this
+	// lock, RetryPolicy's very next attempt could call into the same
+	// *agouti.Page concurrently with it.
+	mu sync.Mutex
+}
+
+// locked wraps fn so it waits its turn on d.mu before running. Every
+// Driver method below that touches page uses it (WaitFor takes d.mu
+// directly around each poll), so a call abandoned on timeout still
+// finishes before the next one touches page, even though the caller
+// that abandoned it has already moved on.
+func (d *agoutiDriver) locked(fn func() error) func() error {
+	return func() error {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return fn()
+	}
+}
+
+// AgoutiDriverFactory starts agouti's ChromeDriver and returns a
+// Driver backed by it. It's the default DriverFactory for both
+// NewClient and a zero-value Client, so code written before Driver
+// existed keeps working unchanged.
+func AgoutiDriverFactory() (Driver, error) {
+	webDriver := agouti.ChromeDriver()
+	if err := webDriver.Start(); err != nil {
+		return nil, err
+	}
+
+	page, err := webDriver.NewPage()
+	if err != nil {
+		webDriver.Stop()
+		return nil, err
+	}
+
+	return &agoutiDriver{webDriver: webDriver, page: page}, nil
+}
+
+// agouti has no native way to cancel a call partway through, so every
+// method below but WaitFor (which checks ctx between polls) and Close
+// runs on runCtx: the underlying agouti call still runs
+// to completion somewhere in the background, but the method returns
+// as soon as ctx says to give up.
+
+func (d *agoutiDriver) Navigate(ctx context.Context, url string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Navigate(url) }))
+}
+
+func (d *agoutiDriver) Fill(ctx context.Context, selector, value string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Find(selector).Fill(value) }))
+}
+
+func (d *agoutiDriver) Click(ctx context.Context, selector string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Find(selector).Click() }))
+}
+
+func (d *agoutiDriver) Text(ctx context.Context, selector string) (string, error) {
+	var text string
+	err := runCtx(ctx, d.locked(func() error {
+		var err error
+		text, err = d.page.Find(selector).Text()
+		return err
+	}))
+	return text, err
+}
+
+func (d *agoutiDriver) HTML(ctx context.Context) (string, error) {
+	var html string
+	err := runCtx(ctx, d.locked(func() error {
+		var err error
+		html, err = d.page.HTML()
+		return err
+	}))
+	return html, err
+}
+
+func (d *agoutiDriver) SwitchFrame(ctx context.Context, selector string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Find(selector).SwitchToFrame() }))
+}
+
+func (d *agoutiDriver) Count(ctx context.Context, selector string) (int, error) {
+	var count int
+	err := runCtx(ctx, d.locked(func() error {
+		var err error
+		count, err = d.page.All(selector).Count()
+		return err
+	}))
+	return count, err
+}
+
+func (d *agoutiDriver) TextAll(ctx context.Context, selector string) ([]string, error) {
+	var texts []string
+	err := runCtx(ctx, d.locked(func() error {
+		selection := d.page.All(selector)
+		n, err := selection.Count()
+		if err != nil {
+			return err
+		}
+
+		texts = make([]string, n)
+		for i := 0; i < n; i++ {
+			text, err := selection.At(i).Text()
+			if err != nil {
+				return err
+			}
+			texts[i] = text
+		}
+		return nil
+	}))
+	return texts, err
+}
+
+func (d *agoutiDriver) Attribute(ctx context.Context, selector, name string) (string, error) {
+	var value string
+	err := runCtx(ctx, d.locked(func() error {
+		var err error
+		value, err = d.page.Find(selector).Attribute(name)
+		return err
+	}))
+	return value, err
+}
+
+func (d *agoutiDriver) PressEnter(ctx context.Context, selector string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Find(selector).SendKeys("\n") }))
+}
+
+func (d *agoutiDriver) ClickLink(ctx context.Context, text string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.FindByLink(text).Click() }))
+}
+
+func (d *agoutiDriver) WaitFor(ctx context.Context, selector string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		d.mu.Lock()
+		count, err := d.page.Find(selector).Count()
+		d.mu.Unlock()
+		if err == nil && count > 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return &ErrElementNotFound{Selector: selector}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (d *agoutiDriver) Close() error {
+	return d.webDriver.Stop()
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// chromedpDriver adapts chromedp -- which drives Chrome over the
+// DevTools Protocol directly, with no external chromedriver binary
+// -- to the Driver interface.
+type chromedpDriver struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ChromedpDriverFactory is a DriverFactory that returns a Driver backed
+// by chromedp, running headless Chrome. It's a drop-in replacement for
+// AgoutiDriverFactory that needs no chromedriver wrapper on PATH:
+//
+//	client := tdbank.NewClient(tdbank.WithDriverFactory(tdbank.ChromedpDriverFactory))
+func ChromedpDriverFactory() (Driver, error) {
+	ctx, cancel := chromedp.NewContext(context.Background())
+
+	// Force the browser to launch now, rather than lazily on first
+	// use, so that a bad Chrome install is reported here instead of
+	// on whatever the first real navigation happens to be.
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &chromedpDriver{ctx: ctx, cancel: cancel}, nil
+}
+
+// actionCtx derives a context for a single chromedp.Run call that's
+// cancelled whenever the caller's ctx is, or after timeout if timeout
+// is positive -- unlike d.ctx itself, which lives for the browser's
+// whole session -- so a per-operation RetryPolicy.Timeout actually
+// aborts a hung action instead of only bounding the delay between
+// retries.
+func (d *chromedpDriver) actionCtx(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	var actionCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		actionCtx, cancel = context.WithTimeout(d.ctx, timeout)
+	} else {
+		actionCtx, cancel = context.WithCancel(d.ctx)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-actionCtx.Done():
+		}
+	}()
+	return actionCtx, cancel
+}
+
+func (d *chromedpDriver) Navigate(ctx context.Context, url string) error {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+	return chromedp.Run(actionCtx, chromedp.Navigate(url))
+}
+
+func (d *chromedpDriver) Fill(ctx context.Context, selector, value string) error {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+	return chromedp.Run(actionCtx, chromedp.SetValue(selector, value, chromedp.ByQuery))
+}
+
+func (d *chromedpDriver) Click(ctx context.Context, selector string) error {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+	return chromedp.Run(actionCtx, chromedp.Click(selector, chromedp.ByQuery))
+}
+
+func (d *chromedpDriver) Text(ctx context.Context, selector string) (string, error) {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+
+	var text string
+	err := chromedp.Run(actionCtx, chromedp.Text(selector, &text, chromedp.ByQuery))
+	return text, err
+}
+
+func (d *chromedpDriver) HTML(ctx context.Context) (string, error) {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+
+	var html string
+	err := chromedp.Run(actionCtx, chromedp.OuterHTML("html", &html, chromedp.ByQuery))
+	return html, err
+}
+
+// SwitchFrame is a no-op for chromedp: its selector-based actions
+// already reach into iframes, so there's no separate frame-switch
+// step the way there is with agouti.
+func (d *chromedpDriver) SwitchFrame(ctx context.Context, selector string) error {
+	return nil
+}
+
+// Count returns the number of elements matching selector. It never
+// errors for zero matches -- AtLeast(0) tells chromedp that's a valid
+// outcome, not a failed query.
+func (d *chromedpDriver) Count(ctx context.Context, selector string) (int, error) {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+
+	var nodes []*cdp.Node
+	err := chromedp.Run(actionCtx, chromedp.Nodes(selector, &nodes, chromedp.ByQueryAll, chromedp.AtLeast(0)))
+	if err != nil {
+		return 0, err
+	}
+	return len(nodes), nil
+}
+
+func (d *chromedpDriver) TextAll(ctx context.Context, selector string) ([]string, error) {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+
+	js := fmt.Sprintf(
+		`Array.prototype.map.call(document.querySelectorAll(%q), function(e){ return e.textContent; })`,
+		selector,
+	)
+
+	var texts []string
+	if err := chromedp.Run(actionCtx, chromedp.Evaluate(js, &texts)); err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+func (d *chromedpDriver) Attribute(ctx context.Context, selector, name string) (string, error) {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+
+	var value string
+	var ok bool
+	if err := chromedp.Run(actionCtx, chromedp.AttributeValue(selector, name, &value, &ok, chromedp.ByQuery)); err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", nil
+	}
+	return value, nil
+}
+
+// PressEnter sends a carriage return to the matched field. "\r" is
+// the same key value chromedp/kb.Enter sends; it's spelled out here
+// rather than importing kb for one constant.
+func (d *chromedpDriver) PressEnter(ctx context.Context, selector string) error {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+	return chromedp.Run(actionCtx, chromedp.SendKeys(selector, "\r", chromedp.ByQuery))
+}
+
+// ClickLink clicks the first <a> whose trimmed text content equals
+// text. chromedp has no built-in text selector, so this reaches for
+// plain JS instead.
+func (d *chromedpDriver) ClickLink(ctx context.Context, text string) error {
+	actionCtx, cancel := d.actionCtx(ctx, 0)
+	defer cancel()
+
+	js := fmt.Sprintf(`(function(){
+		var links = document.getElementsByTagName("a");
+		for (var i = 0; i < links.length; i++) {
+			if (links[i].textContent.trim() === %q) {
+				links[i].click();
+				return true;
+			}
+		}
+		return false;
+	})()`, text)
+
+	var found bool
+	if err := chromedp.Run(actionCtx, chromedp.Evaluate(js, &found)); err != nil {
+		return err
+	}
+	if !found {
+		return &ErrElementNotFound{Selector: "link: " + text}
+	}
+	return nil
+}
+
+func (d *chromedpDriver) WaitFor(ctx context.Context, selector string, timeout time.Duration) error {
+	actionCtx, cancel := d.actionCtx(ctx, timeout)
+	defer cancel()
+	return chromedp.Run(actionCtx, chromedp.WaitVisible(selector, chromedp.ByQuery))
+}
+
+func (d *chromedpDriver) Close() error {
+	d.cancel()
+	return nil
+}
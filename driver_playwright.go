@@ -0,0 +1,189 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// playwrightDriver adapts playwright-go to the Driver interface.
+type playwrightDriver struct {
+	pw      *playwright.Playwright
+	browser playwright.Browser
+	page    playwright.Page
+
+	// mu serializes every call into page. playwright-go has no way
+	// to abort a call in flight, so a call abandoned by runCtx on
+	// timeout keeps running against page in the background; without
+	// this lock, RetryPolicy's very next attempt could call into the
+	// same playwright.Page concurrently with it.
+	mu sync.Mutex
+}
+
+// locked wraps fn so it waits its turn on d.mu before running. Every
+// Driver method below uses it, so a call abandoned on timeout still
+// finishes before the next one touches page, even though the caller
+// that abandoned it has already moved on.
+func (d *playwrightDriver) locked(fn func() error) func() error {
+	return func() error {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return fn()
+	}
+}
+
+// PlaywrightDriverFactory is a DriverFactory that returns a Driver
+// backed by Playwright, running headless Chromium. Like ChromedpDriverFactory,
+// it needs no external chromedriver binary, and it brings
+// Playwright's network interception and screenshot support along for
+// debugging failed scrapes.
+func PlaywrightDriverFactory() (Driver, error) {
+	pw, err := playwright.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	browser, err := pw.Chromium.Launch()
+	if err != nil {
+		pw.Stop()
+		return nil, err
+	}
+
+	page, err := browser.NewPage()
+	if err != nil {
+		browser.Close()
+		pw.Stop()
+		return nil, err
+	}
+
+	return &playwrightDriver{pw: pw, browser: browser, page: page}, nil
+}
+
+// playwright-go has no native way to cancel a call partway through,
+// so every method below runs on runCtx: the underlying Playwright call
+// still runs to completion somewhere in the background, but the
+// method returns as soon as ctx says to give up.
+
+func (d *playwrightDriver) Navigate(ctx context.Context, url string) error {
+	return runCtx(ctx, d.locked(func() error {
+		_, err := d.page.Goto(url)
+		return err
+	}))
+}
+
+func (d *playwrightDriver) Fill(ctx context.Context, selector, value string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Fill(selector, value) }))
+}
+
+func (d *playwrightDriver) Click(ctx context.Context, selector string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Click(selector) }))
+}
+
+func (d *playwrightDriver) Text(ctx context.Context, selector string) (string, error) {
+	var text string
+	err := runCtx(ctx, d.locked(func() error {
+		var err error
+		text, err = d.page.InnerText(selector)
+		return err
+	}))
+	return text, err
+}
+
+func (d *playwrightDriver) HTML(ctx context.Context) (string, error) {
+	var html string
+	err := runCtx(ctx, d.locked(func() error {
+		var err error
+		html, err = d.page.Content()
+		return err
+	}))
+	return html, err
+}
+
+// SwitchFrame is a no-op for Playwright: reaching into a frame means
+// locating it and calling ContentFrame, which needs a frame handle
+// the Driver interface doesn't expose. Callers on this backend should
+// select elements through the frame directly if they need to.
+func (d *playwrightDriver) SwitchFrame(ctx context.Context, selector string) error {
+	return nil
+}
+
+func (d *playwrightDriver) Count(ctx context.Context, selector string) (int, error) {
+	var count int
+	err := runCtx(ctx, d.locked(func() error {
+		elements, err := d.page.QuerySelectorAll(selector)
+		if err != nil {
+			return err
+		}
+		count = len(elements)
+		return nil
+	}))
+	return count, err
+}
+
+func (d *playwrightDriver) TextAll(ctx context.Context, selector string) ([]string, error) {
+	var texts []string
+	err := runCtx(ctx, d.locked(func() error {
+		result, err := d.page.EvalOnSelectorAll(selector, "els => els.map(e => e.textContent)")
+		if err != nil {
+			return err
+		}
+
+		items, ok := result.([]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected result from EvalOnSelectorAll: %T", result)
+		}
+
+		texts = make([]string, len(items))
+		for i, item := range items {
+			if text, ok := item.(string); ok {
+				texts[i] = text
+			}
+		}
+		return nil
+	}))
+	return texts, err
+}
+
+func (d *playwrightDriver) Attribute(ctx context.Context, selector, name string) (string, error) {
+	var value string
+	err := runCtx(ctx, d.locked(func() error {
+		var err error
+		value, err = d.page.GetAttribute(selector, name)
+		return err
+	}))
+	return value, err
+}
+
+func (d *playwrightDriver) PressEnter(ctx context.Context, selector string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Press(selector, "Enter") }))
+}
+
+// ClickLink clicks the first anchor whose visible text equals text,
+// using Playwright's own :text-is() selector engine.
+func (d *playwrightDriver) ClickLink(ctx context.Context, text string) error {
+	return runCtx(ctx, d.locked(func() error { return d.page.Click(fmt.Sprintf(`a:text-is(%q)`, text)) }))
+}
+
+func (d *playwrightDriver) WaitFor(ctx context.Context, selector string, timeout time.Duration) error {
+	return runCtx(ctx, d.locked(func() error {
+		ms := float64(timeout / time.Millisecond)
+		_, err := d.page.WaitForSelector(selector, playwright.PageWaitForSelectorOptions{
+			Timeout: &ms,
+		})
+		return err
+	}))
+}
+
+func (d *playwrightDriver) Close() error {
+	if err := d.browser.Close(); err != nil {
+		return err
+	}
+	return d.pw.Stop()
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import "fmt"
+
+// ErrLoginFailed indicates that Login couldn't authenticate with the
+// bank, whether because a form field couldn't be filled in or because
+// the bank's site behaved unexpectedly partway through the flow.
+type ErrLoginFailed struct {
+	Reason string
+	Err    error
+}
+
+func (e *ErrLoginFailed) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("login failed (%s): %v", e.Reason, e.Err)
+	}
+	return fmt.Sprintf("login failed: %s", e.Reason)
+}
+
+func (e *ErrLoginFailed) Unwrap() error { return e.Err }
+
+// ErrSecurityQuestion indicates that the bank asked a security
+// question for which Auth.SecurityQuestions had no matching answer.
+type ErrSecurityQuestion struct {
+	Question string
+}
+
+func (e *ErrSecurityQuestion) Error() string {
+	return fmt.Sprintf("no answer configured for security question: %q", e.Question)
+}
+
+// ErrElementNotFound indicates that an expected link, button, or
+// form field wasn't present on the page. This usually means the
+// bank's markup changed, or the browser ended up somewhere other
+// than where the caller expected.
+type ErrElementNotFound struct {
+	Selector string
+	Err      error
+}
+
+func (e *ErrElementNotFound) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("element not found (%s): %v", e.Selector, e.Err)
+	}
+	return fmt.Sprintf("element not found: %s", e.Selector)
+}
+
+func (e *ErrElementNotFound) Unwrap() error { return e.Err }
+
+// ErrNavigation indicates that the browser failed to navigate to a
+// URL. Login returns it when the login page can't be loaded; a link
+// that can't be clicked is reported as ErrElementNotFound instead.
+type ErrNavigation struct {
+	URL string
+	Err error
+}
+
+func (e *ErrNavigation) Error() string {
+	return fmt.Sprintf("failed to navigate to %s: %v", e.URL, e.Err)
+}
+
+func (e *ErrNavigation) Unwrap() error { return e.Err }
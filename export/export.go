@@ -0,0 +1,296 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package export serializes parsed account histories into common
+// personal-finance interchange formats -- OFX, QFX, CSV, and JSON --
+// so that they can be imported directly into GnuCash, Quicken, or a
+// database. It's the natural companion to the scraping done by the
+// tdbank package: once you have a []tdbank.HistoryRecord, this is how
+// you get it somewhere useful.
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budney/tdbank"
+)
+
+// AccountInfo identifies the account that a set of HistoryRecords
+// belongs to. It's needed because TD Bank's account history page
+// doesn't always carry an explicit account number or bank ID, but
+// OFX and QFX require both.
+type AccountInfo struct {
+	BankID   string // routing number, or any stable identifier for the bank
+	AcctID   string // account number, or a masked equivalent
+	AcctType string // "CHECKING", "SAVINGS", "CREDITLINE", or "CREDITCARD"
+	Currency string // ISO 4217 currency code; defaults to "USD" if empty
+}
+
+// currency returns the account's currency code, defaulting to USD.
+func (acct AccountInfo) currency() string {
+	if acct.Currency == "" {
+		return "USD"
+	}
+	return acct.Currency
+}
+
+// ofxDateFormat is the OFX/QFX date-time format: YYYYMMDDHHMMSS.
+const ofxDateFormat = "20060102150405"
+
+// sgmlEscaper replaces the characters OFX 1.x SGML reserves -- &, <,
+// and > -- with their entity equivalents. OFX 1.x's entity set is a
+// subset of HTML's, but this is exactly the set every field written
+// into an unquoted SGML tag (like <NAME>) needs escaped, since a bare
+// "&" or "<" in a transaction description would otherwise corrupt the
+// tag structure or be read back as the start of another entity.
+var sgmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeSGML makes s safe to write as the content of an OFX 1.x SGML
+// tag.
+func escapeSGML(s string) string {
+	return sgmlEscaper.Replace(s)
+}
+
+// escapeXML makes s safe to write as the content of an OFX 2.x/QFX
+// XML element, using encoding/xml's own escaping rules rather than
+// reimplementing them.
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// errWriter wraps an io.Writer, remembering the first error any write
+// hits and silently skipping the rest -- the usual way to avoid
+// checking an error after each of the dozens of individual writes
+// WriteOFX and WriteQFX make.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) print(s string) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprint(ew.w, s)
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
+
+// WriteOFX writes records as an OFX 1.x SGML bank statement response,
+// suitable for import into GnuCash or any other OFX 1.x consumer. The
+// output contains a single <BANKMSGSRSV1>/<STMTRS> block with one
+// <STMTTRN> per record and a <LEDGERBAL> taken from the last record's
+// balance.
+func WriteOFX(w io.Writer, acct AccountInfo, records []tdbank.HistoryRecord) error {
+	now := time.Now().Format(ofxDateFormat)
+	ew := &errWriter{w: w}
+
+	ew.print("OFXHEADER:100\r\n")
+	ew.print("DATA:OFXSGML\r\n")
+	ew.print("VERSION:102\r\n")
+	ew.print("SECURITY:NONE\r\n")
+	ew.print("ENCODING:USASCII\r\n")
+	ew.print("CHARSET:1252\r\n")
+	ew.print("COMPRESSION:NONE\r\n")
+	ew.print("OLDFILEUID:NONE\r\n")
+	ew.print("NEWFILEUID:NONE\r\n\r\n")
+
+	ew.print("<OFX>\r\n")
+	ew.print("<SIGNONMSGSRSV1>\r\n<SONRS>\r\n")
+	ew.print("<STATUS><CODE>0<SEVERITY>INFO</STATUS>\r\n")
+	ew.printf("<DTSERVER>%s\r\n", now)
+	ew.print("<LANGUAGE>ENG\r\n")
+	ew.print("</SONRS>\r\n</SIGNONMSGSRSV1>\r\n")
+
+	ew.print("<BANKMSGSRSV1>\r\n<STMTTRNRS>\r\n")
+	ew.print("<TRNUID>0\r\n")
+	ew.print("<STATUS><CODE>0<SEVERITY>INFO</STATUS>\r\n")
+	ew.print("<STMTRS>\r\n")
+	ew.printf("<CURDEF>%s\r\n", acct.currency())
+	ew.print("<BANKACCTFROM>\r\n")
+	ew.printf("<BANKID>%s\r\n", escapeSGML(acct.BankID))
+	ew.printf("<ACCTID>%s\r\n", escapeSGML(acct.AcctID))
+	ew.printf("<ACCTTYPE>%s\r\n", escapeSGML(acct.AcctType))
+	ew.print("</BANKACCTFROM>\r\n")
+
+	ew.print("<BANKTRANLIST>\r\n")
+	if len(records) > 0 {
+		ew.printf("<DTSTART>%s\r\n", records[0].Date.Format(ofxDateFormat))
+		ew.printf("<DTEND>%s\r\n", records[len(records)-1].Date.Format(ofxDateFormat))
+	}
+	for _, record := range records {
+		ew.print("<STMTTRN>\r\n")
+		ew.printf("<TRNTYPE>%s\r\n", trnType(record))
+		ew.printf("<DTPOSTED>%s\r\n", record.Date.Format(ofxDateFormat))
+		ew.printf("<TRNAMT>%s\r\n", dollars(netAmount(record)))
+		ew.printf("<FITID>%s\r\n", fitID(record))
+		ew.printf("<NAME>%s\r\n", escapeSGML(record.Description))
+		ew.print("</STMTTRN>\r\n")
+	}
+	ew.print("</BANKTRANLIST>\r\n")
+
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		ew.print("<LEDGERBAL>\r\n")
+		ew.printf("<BALAMT>%s\r\n", dollars(last.Balance))
+		ew.printf("<DTASOF>%s\r\n", last.Date.Format(ofxDateFormat))
+		ew.print("</LEDGERBAL>\r\n")
+	}
+
+	ew.print("</STMTRS>\r\n</STMTTRNRS>\r\n</BANKMSGSRSV1>\r\n")
+	ew.print("</OFX>\r\n")
+
+	return ew.err
+}
+
+// WriteQFX writes records as an OFX 2.x XML bank statement response --
+// the format Quicken expects when it's handed a .qfx file. The
+// structure mirrors WriteOFX; only the envelope is XML instead of
+// SGML.
+func WriteQFX(w io.Writer, acct AccountInfo, records []tdbank.HistoryRecord) error {
+	now := time.Now().Format(ofxDateFormat)
+	ew := &errWriter{w: w}
+
+	ew.print("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	ew.print("<?OFX OFXHEADER=\"200\" VERSION=\"211\" SECURITY=\"NONE\" OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n")
+
+	ew.print("<OFX>\n")
+	ew.print("<SIGNONMSGSRSV1><SONRS>\n")
+	ew.print("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	ew.printf("<DTSERVER>%s</DTSERVER>\n", now)
+	ew.print("<LANGUAGE>ENG</LANGUAGE>\n")
+	ew.print("</SONRS></SIGNONMSGSRSV1>\n")
+
+	ew.print("<BANKMSGSRSV1><STMTTRNRS>\n")
+	ew.print("<TRNUID>0</TRNUID>\n")
+	ew.print("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	ew.print("<STMTRS>\n")
+	ew.printf("<CURDEF>%s</CURDEF>\n", acct.currency())
+	ew.print("<BANKACCTFROM>\n")
+	ew.printf("<BANKID>%s</BANKID>\n", escapeXML(acct.BankID))
+	ew.printf("<ACCTID>%s</ACCTID>\n", escapeXML(acct.AcctID))
+	ew.printf("<ACCTTYPE>%s</ACCTTYPE>\n", escapeXML(acct.AcctType))
+	ew.print("</BANKACCTFROM>\n")
+
+	ew.print("<BANKTRANLIST>\n")
+	if len(records) > 0 {
+		ew.printf("<DTSTART>%s</DTSTART>\n", records[0].Date.Format(ofxDateFormat))
+		ew.printf("<DTEND>%s</DTEND>\n", records[len(records)-1].Date.Format(ofxDateFormat))
+	}
+	for _, record := range records {
+		ew.print("<STMTTRN>\n")
+		ew.printf("<TRNTYPE>%s</TRNTYPE>\n", trnType(record))
+		ew.printf("<DTPOSTED>%s</DTPOSTED>\n", record.Date.Format(ofxDateFormat))
+		ew.printf("<TRNAMT>%s</TRNAMT>\n", dollars(netAmount(record)))
+		ew.printf("<FITID>%s</FITID>\n", fitID(record))
+		ew.printf("<NAME>%s</NAME>\n", escapeXML(record.Description))
+		ew.print("</STMTTRN>\n")
+	}
+	ew.print("</BANKTRANLIST>\n")
+
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		ew.print("<LEDGERBAL>\n")
+		ew.printf("<BALAMT>%s</BALAMT>\n", dollars(last.Balance))
+		ew.printf("<DTASOF>%s</DTASOF>\n", last.Date.Format(ofxDateFormat))
+		ew.print("</LEDGERBAL>\n")
+	}
+
+	ew.print("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n")
+	ew.print("</OFX>\n")
+
+	return ew.err
+}
+
+// WriteCSV writes records as CSV, one row per record, with a header
+// row of field names. Debit, Credit, and Balance are rendered in
+// dollars and cents.
+func WriteCSV(w io.Writer, acct AccountInfo, records []tdbank.HistoryRecord) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"Date", "Type", "Description", "Debit", "Credit", "Balance"}); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := []string{
+			record.Date.Format("01/02/2006"),
+			record.Type,
+			record.Description,
+			dollars(record.Debit),
+			dollars(record.Credit),
+			dollars(record.Balance),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes records as a JSON array, in the same shape as
+// tdbank.HistoryRecord itself.
+func WriteJSON(w io.Writer, acct AccountInfo, records []tdbank.HistoryRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// trnType derives an OFX TRNTYPE from the sign of a record's amount.
+func trnType(record tdbank.HistoryRecord) string {
+	if netAmount(record) > 0 {
+		return "CREDIT"
+	}
+	return "DEBIT"
+}
+
+// netAmount returns a record's amount as a signed number of pennies:
+// positive for a credit, negative for a debit.
+func netAmount(record tdbank.HistoryRecord) int64 {
+	return record.Credit - record.Debit
+}
+
+// fitID synthesizes an OFX FITID (financial institution transaction
+// ID) from a record's date, index, and amount, so that importing the
+// same date range twice doesn't create duplicate transactions.
+func fitID(record tdbank.HistoryRecord) string {
+	return fmt.Sprintf("%s-%d-%d", record.Date.Format("20060102"), record.Index, netAmount(record))
+}
+
+// dollars formats a quantity of pennies as a decimal dollar amount,
+// e.g. 12345 becomes "123.45".
+func dollars(pennies int64) string {
+	negative := pennies < 0
+	if negative {
+		pennies = -pennies
+	}
+
+	s := strconv.FormatInt(pennies, 10)
+	for len(s) < 3 {
+		s = "0" + s
+	}
+	whole, cents := s[:len(s)-2], s[len(s)-2:]
+
+	if negative {
+		return "-" + whole + "." + cents
+	}
+	return whole + "." + cents
+}
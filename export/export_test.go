@@ -0,0 +1,103 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/budney/tdbank"
+)
+
+func TestDollars(t *testing.T) {
+	cases := []struct {
+		pennies int64
+		want    string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{100, "1.00"},
+		{12345, "123.45"},
+		{-12345, "-123.45"},
+	}
+
+	for _, c := range cases {
+		if got := dollars(c.pennies); got != c.want {
+			t.Errorf("dollars(%d) = %q, want %q", c.pennies, got, c.want)
+		}
+	}
+}
+
+func TestTrnType(t *testing.T) {
+	if got := trnType(tdbank.HistoryRecord{Credit: 100}); got != "CREDIT" {
+		t.Errorf("trnType(credit) = %q, want CREDIT", got)
+	}
+	if got := trnType(tdbank.HistoryRecord{Debit: 100}); got != "DEBIT" {
+		t.Errorf("trnType(debit) = %q, want DEBIT", got)
+	}
+}
+
+func TestFitID(t *testing.T) {
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	a := fitID(tdbank.HistoryRecord{Date: date, Index: 1, Debit: 500})
+	b := fitID(tdbank.HistoryRecord{Date: date, Index: 2, Debit: 500})
+	if a == b {
+		t.Errorf("fitID should differ by Index, got %q for both", a)
+	}
+
+	want := "20240315-1--500"
+	if a != want {
+		t.Errorf("fitID = %q, want %q", a, want)
+	}
+}
+
+// records with descriptions like "AT&T PAYMENT" are ordinary real-world
+// bank data; WriteOFX and WriteQFX must escape them rather than
+// emitting invalid SGML/XML.
+var specialCharRecords = []tdbank.HistoryRecord{
+	{
+		Date:        time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		Description: `AT&T PAYMENT <online> "fee" & more`,
+		Debit:       500,
+		Balance:     9500,
+	},
+}
+
+func TestWriteQFXEscapesSpecialCharacters(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteQFX(&buf, AccountInfo{BankID: "R&D", AcctID: "1234"}, specialCharRecords); err != nil {
+		t.Fatalf("WriteQFX: %v", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"OFX"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteQFX output isn't valid XML: %v\n%s", err, buf.String())
+	}
+
+	if strings.Contains(buf.String(), "<NAME>AT&T") {
+		t.Errorf("WriteQFX output contains an unescaped &: %s", buf.String())
+	}
+}
+
+func TestWriteOFXEscapesSpecialCharacters(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteOFX(&buf, AccountInfo{BankID: "R&D", AcctID: "1234"}, specialCharRecords); err != nil {
+		t.Fatalf("WriteOFX: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "<NAME>AT&T") {
+		t.Errorf("WriteOFX output contains an unescaped &: %s", out)
+	}
+	if !strings.Contains(out, "AT&amp;T PAYMENT &lt;online&gt;") {
+		t.Errorf("WriteOFX output missing expected escaped description: %s", out)
+	}
+}
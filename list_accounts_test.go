@@ -0,0 +1,86 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeDriver is a minimal Driver whose Count/Text/TextAll results are
+// canned by exact selector, just enough to drive ListAccounts without
+// a real browser.
+type fakeDriver struct {
+	counts  map[string]int
+	texts   map[string]string
+	textAll map[string][]string
+}
+
+func (d *fakeDriver) Navigate(context.Context, string) error                    { return nil }
+func (d *fakeDriver) Fill(context.Context, string, string) error                { return nil }
+func (d *fakeDriver) Click(context.Context, string) error                       { return nil }
+func (d *fakeDriver) HTML(context.Context) (string, error)                      { return "", nil }
+func (d *fakeDriver) SwitchFrame(context.Context, string) error                 { return nil }
+func (d *fakeDriver) WaitFor(context.Context, string, time.Duration) error      { return nil }
+func (d *fakeDriver) Attribute(context.Context, string, string) (string, error) { return "", nil }
+func (d *fakeDriver) PressEnter(context.Context, string) error                  { return nil }
+func (d *fakeDriver) ClickLink(context.Context, string) error                   { return nil }
+func (d *fakeDriver) Close() error                                              { return nil }
+
+func (d *fakeDriver) Text(ctx context.Context, selector string) (string, error) {
+	return d.texts[selector], nil
+}
+func (d *fakeDriver) Count(ctx context.Context, selector string) (int, error) {
+	return d.counts[selector], nil
+}
+func (d *fakeDriver) TextAll(ctx context.Context, selector string) ([]string, error) {
+	return d.textAll[selector], nil
+}
+
+func TestListAccounts(t *testing.T) {
+	drv := &fakeDriver{
+		counts: map[string]int{AccountsSelector: 3},
+		texts: map[string]string{
+			AccountsSelector + ":nth-child(1) a": "",
+			AccountsSelector + ":nth-child(1)":   "Checking",
+			AccountsSelector + ":nth-child(2) a": "Main Checking",
+			AccountsSelector + ":nth-child(3) a": "Secondary Checking",
+		},
+		textAll: map[string][]string{
+			AccountsSelector + ":nth-child(2) td": {"Main Checking", "xxxx1234", "$1,234.56"},
+			AccountsSelector + ":nth-child(3) td": {"Secondary Checking", "5678", "$42.00"},
+		},
+	}
+
+	client := &Client{drv: drv}
+
+	accounts, err := client.ListAccounts()
+	if err != nil {
+		t.Fatalf("ListAccounts: %v", err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("got %d accounts, want 2", len(accounts))
+	}
+
+	if accounts[0].Type != "checking" {
+		t.Errorf("accounts[0].Type = %q, want checking", accounts[0].Type)
+	}
+	if accounts[0].MaskedNumber != "xxxx1234" {
+		t.Errorf("accounts[0].MaskedNumber = %q, want xxxx1234", accounts[0].MaskedNumber)
+	}
+	if accounts[0].Balance != 123456 {
+		t.Errorf("accounts[0].Balance = %d, want 123456", accounts[0].Balance)
+	}
+
+	// The masked number here is plain digits, with no currency symbol
+	// or decimal point -- it must not be mistaken for the balance.
+	if accounts[1].MaskedNumber != "5678" {
+		t.Errorf("accounts[1].MaskedNumber = %q, want 5678", accounts[1].MaskedNumber)
+	}
+	if accounts[1].Balance != 4200 {
+		t.Errorf("accounts[1].Balance = %d, want 4200", accounts[1].Balance)
+	}
+}
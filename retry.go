@@ -0,0 +1,101 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// A RetryPolicy controls how a Client retries a transient failure
+// talking to the bank's web site -- a DOM race, a slow page load, a
+// dropped connection. An interaction is retried up to MaxAttempts
+// times total, with exponential backoff (doubling from BaseDelay,
+// full jitter applied) between attempts, and each attempt bounded by
+// Timeout via its context.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	Timeout     time.Duration
+}
+
+// DefaultRetryPolicy is used by Client whenever its Retry field is
+// left at its zero value.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   250 * time.Millisecond,
+	Timeout:     30 * time.Second,
+}
+
+// retryPolicy returns client.Retry, or DefaultRetryPolicy if it
+// hasn't been set.
+func (client *Client) retryPolicy() RetryPolicy {
+	if client.Retry == (RetryPolicy{}) {
+		return DefaultRetryPolicy
+	}
+	return client.Retry
+}
+
+// retry runs op, retrying according to policy until it succeeds, ctx
+// is cancelled, or MaxAttempts is reached. Each attempt is given its
+// own sub-context bounded by Timeout.
+func (policy RetryPolicy) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if waitErr := sleep(ctx, policy.jitteredDelay(attempt)); waitErr != nil {
+				return waitErr
+			}
+		}
+
+		opCtx := ctx
+		var cancel context.CancelFunc
+		if policy.Timeout > 0 {
+			opCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		}
+
+		err = op(opCtx)
+		if cancel != nil {
+			cancel()
+		}
+
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// jitteredDelay returns the backoff delay before the given retry
+// attempt (1-indexed): BaseDelay doubled once per prior attempt, with
+// full jitter -- a random duration between zero and that value.
+func (policy RetryPolicy) jitteredDelay(attempt int) time.Duration {
+	max := policy.BaseDelay << uint(attempt-1)
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// sleep waits for d, returning early with ctx.Err() if ctx is
+// cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
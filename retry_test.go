@@ -0,0 +1,78 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tdbank
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJitteredDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond}
+
+	cases := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		for i := 0; i < 50; i++ {
+			delay := policy.jitteredDelay(c.attempt)
+			if delay < 0 || delay > c.max {
+				t.Fatalf("jitteredDelay(%d) = %v, want in [0, %v]", c.attempt, delay, c.max)
+			}
+		}
+	}
+}
+
+func TestJitteredDelayZeroBaseDelay(t *testing.T) {
+	policy := RetryPolicy{}
+	if delay := policy.jitteredDelay(1); delay != 0 {
+		t.Errorf("jitteredDelay with zero BaseDelay = %v, want 0", delay)
+	}
+}
+
+// TestRetryTimeoutPreemptsHungOp proves that Timeout actually bounds
+// each attempt: an op that blocks until its context is done must be
+// handed a per-attempt context that expires after Timeout, rather
+// than one that leaves it hanging the caller forever.
+func TestRetryTimeoutPreemptsHungOp(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 1,
+		Timeout:     10 * time.Millisecond,
+	}
+
+	err := policy.retry(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("retry() = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// TestRetrySucceedsWithinTimeout confirms a Timeout that's generous
+// enough doesn't get in the way of an op that actually succeeds.
+func TestRetrySucceedsWithinTimeout(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 1,
+		Timeout:     time.Second,
+	}
+
+	err := policy.retry(context.Background(), func(ctx context.Context) error {
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("retry() = %v, want nil", err)
+	}
+}
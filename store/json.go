@@ -0,0 +1,157 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package store provides pluggable persistence for tdbank.HistoryRecord
+// values, so that a scrape can be synced into a durable ledger instead
+// of thrown away at the end of the process. JSONStore, SQLiteStore,
+// and PostgresStore all implement tdbank.Store.
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/budney/tdbank"
+)
+
+// jsonRecord is the on-disk shape of a stored HistoryRecord: the
+// record itself plus the account it belongs to and its dedup hash.
+type jsonRecord struct {
+	Account string               `json:"account"`
+	Hash    string               `json:"hash"`
+	Record  tdbank.HistoryRecord `json:"record"`
+}
+
+// A JSONStore persists records to a single flat JSON file. It's
+// meant for personal use on a single machine -- it reads and
+// rewrites the whole file on every Upsert, so it isn't a good fit
+// for large histories or concurrent writers in different processes.
+type JSONStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewJSONStore returns a JSONStore backed by the file at path. The
+// file is created by the first Upsert that adds a record, if it
+// doesn't already exist.
+func NewJSONStore(path string) *JSONStore {
+	return &JSONStore{path: path}
+}
+
+func (store *JSONStore) load() ([]jsonRecord, error) {
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []jsonRecord
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (store *JSONStore) save(records []jsonRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(store.path, data, 0600)
+}
+
+// Upsert adds any records not already present (by hash) to the file.
+func (store *JSONStore) Upsert(account string, records []tdbank.HistoryRecord) (added, skipped int, err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, err := store.load()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		seen[r.Account+"|"+r.Hash] = true
+	}
+
+	for _, record := range records {
+		key := account + "|" + record.Hash()
+		if seen[key] {
+			skipped++
+			continue
+		}
+		seen[key] = true
+		existing = append(existing, jsonRecord{Account: account, Hash: record.Hash(), Record: record})
+		added++
+	}
+
+	if added > 0 {
+		if err := store.save(existing); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return added, skipped, nil
+}
+
+// Latest returns the date of the most recent record on file for
+// account, or the zero time if there are none.
+func (store *JSONStore) Latest(account string) (time.Time, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, err := store.load()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var latest time.Time
+	for _, r := range existing {
+		if r.Account == account && r.Record.Date.After(latest) {
+			latest = r.Record.Date
+		}
+	}
+	return latest, nil
+}
+
+// Range returns every record on file for account whose date falls
+// between from and to, inclusive, sorted by date and index.
+func (store *JSONStore) Range(account string, from, to time.Time) ([]tdbank.HistoryRecord, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, err := store.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []tdbank.HistoryRecord
+	for _, r := range existing {
+		if r.Account != account {
+			continue
+		}
+		if r.Record.Date.Before(from) || r.Record.Date.After(to) {
+			continue
+		}
+		result = append(result, r.Record)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.Before(result[j].Date)
+		}
+		return result[i].Index < result[j].Index
+	})
+
+	return result, nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"os"
+	"testing"
+
+	"github.com/budney/tdbank"
+)
+
+// TestPostgresStore runs the same contract SQLiteStore does, against
+// a real PostgreSQL database named by TDBANK_TEST_POSTGRES_DSN. There's
+// no PostgreSQL server in CI, so the test skips itself when that's
+// unset rather than needing a build tag.
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("TDBANK_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TDBANK_TEST_POSTGRES_DSN not set; skipping PostgresStore test")
+	}
+
+	testStoreContract(t, func() tdbank.Store {
+		db, err := NewPostgresStore(dsn)
+		if err != nil {
+			t.Fatalf("NewPostgresStore: %v", err)
+		}
+		return db
+	})
+}
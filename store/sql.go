@@ -0,0 +1,149 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/budney/tdbank"
+)
+
+// schema is the table used by both the SQLite and PostgreSQL stores.
+// hash is the record's content hash (tdbank.HistoryRecord.Hash), and
+// together with account it forms the primary key that makes Upsert
+// idempotent. extra holds HistoryRecord.Extra JSON-encoded, since
+// it's an open-ended set of columns (credit-card statements add
+// Category, Rewards, and so on) that doesn't fit a fixed schema.
+const schema = `
+CREATE TABLE IF NOT EXISTS history (
+	account     TEXT NOT NULL,
+	hash        TEXT NOT NULL,
+	date        TIMESTAMP NOT NULL,
+	type        TEXT NOT NULL,
+	description TEXT NOT NULL,
+	debit       BIGINT NOT NULL,
+	credit      BIGINT NOT NULL,
+	balance     BIGINT NOT NULL,
+	idx         INTEGER NOT NULL,
+	extra       TEXT NOT NULL,
+	PRIMARY KEY (account, hash)
+)`
+
+// sqlStore implements tdbank.Store on top of database/sql. SQLiteStore
+// and PostgresStore are thin wrappers around it that differ only in
+// the driver they open and the placeholder syntax they use for
+// parameterized queries (SQLite takes "?"; PostgreSQL takes "$1",
+// "$2", and so on).
+type sqlStore struct {
+	db *sql.DB
+
+	// placeholder returns the parameter marker for the n'th bind
+	// variable (1-indexed) in an INSERT/SELECT statement.
+	placeholder func(n int) string
+}
+
+func newSQLStore(db *sql.DB, placeholder func(n int) string) (*sqlStore, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("creating history table: %w", err)
+	}
+	return &sqlStore{db: db, placeholder: placeholder}, nil
+}
+
+func (store *sqlStore) Upsert(account string, records []tdbank.HistoryRecord) (added, skipped int, err error) {
+	p := store.placeholder
+	query := fmt.Sprintf(
+		`INSERT INTO history (account, hash, date, type, description, debit, credit, balance, idx, extra)
+		 VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+		 ON CONFLICT (account, hash) DO NOTHING`,
+		p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8), p(9), p(10))
+
+	for _, record := range records {
+		extra, err := json.Marshal(record.Extra)
+		if err != nil {
+			return added, skipped, fmt.Errorf("encoding record.Extra: %w", err)
+		}
+
+		result, err := store.db.Exec(query,
+			account, record.Hash(), record.Date, record.Type, record.Description,
+			record.Debit, record.Credit, record.Balance, record.Index, string(extra))
+		if err != nil {
+			return added, skipped, fmt.Errorf("upserting record: %w", err)
+		}
+
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			added++
+		} else {
+			skipped++
+		}
+	}
+
+	return added, skipped, nil
+}
+
+func (store *sqlStore) Latest(account string) (time.Time, error) {
+	query := fmt.Sprintf(`SELECT MAX(date) FROM history WHERE account = %s`, store.placeholder(1))
+
+	var latest interface{}
+	if err := store.db.QueryRow(query, account).Scan(&latest); err != nil {
+		return time.Time{}, err
+	}
+	return parseDate(latest)
+}
+
+// parseDate converts a database/sql scan result for a date/timestamp
+// column into a time.Time. PostgreSQL's driver hands back a time.Time
+// directly; SQLite's driver loses the column's declared type on an
+// aggregate like MAX(date), and hands back the value it stored
+// instead, as a string in the same format it used to write it.
+func parseDate(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse("2006-01-02 15:04:05.999999999-07:00", v)
+	case []byte:
+		return time.Parse("2006-01-02 15:04:05.999999999-07:00", string(v))
+	default:
+		return time.Time{}, fmt.Errorf("unexpected type %T for date column", value)
+	}
+}
+
+func (store *sqlStore) Range(account string, from, to time.Time) ([]tdbank.HistoryRecord, error) {
+	query := fmt.Sprintf(
+		`SELECT date, type, description, debit, credit, balance, idx, extra
+		 FROM history
+		 WHERE account = %s AND date >= %s AND date <= %s
+		 ORDER BY date, idx`,
+		store.placeholder(1), store.placeholder(2), store.placeholder(3))
+
+	rows, err := store.db.Query(query, account, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []tdbank.HistoryRecord
+	for rows.Next() {
+		var record tdbank.HistoryRecord
+		var extra string
+		if err := rows.Scan(&record.Date, &record.Type, &record.Description,
+			&record.Debit, &record.Credit, &record.Balance, &record.Index, &extra); err != nil {
+			return nil, err
+		}
+		if extra != "" && extra != "null" {
+			if err := json.Unmarshal([]byte(extra), &record.Extra); err != nil {
+				return nil, fmt.Errorf("decoding record.Extra: %w", err)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
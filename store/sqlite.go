@@ -0,0 +1,40 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// A SQLiteStore persists records to a SQLite database file. It's the
+// easiest Store to get started with, since it needs no server: point
+// it at a path and it creates the file (and the schema) if needed.
+type SQLiteStore struct {
+	*sqlStore
+}
+
+// NewSQLiteStore opens (and if necessary creates) a SQLite database
+// at path and returns a Store backed by it.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := newSQLStore(db, sqlitePlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLiteStore{sqlStore: inner}, nil
+}
+
+// sqlitePlaceholder returns SQLite's "?" bind marker. SQLite doesn't
+// use numbered placeholders, so n is ignored.
+func sqlitePlaceholder(n int) string {
+	return "?"
+}
@@ -0,0 +1,21 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/budney/tdbank"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	testStoreContract(t, func() tdbank.Store {
+		db, err := NewSQLiteStore(":memory:")
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		return db
+	})
+}
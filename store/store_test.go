@@ -0,0 +1,94 @@
+// Copyright 2017 Len Budney. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/budney/tdbank"
+)
+
+// testStoreContract exercises the behavior every tdbank.Store
+// implementation promises: dedup-by-hash on Upsert, Latest tracking
+// the most recent date on file, Range's date-boundary filtering, and
+// Extra round-tripping through whatever the backend uses to persist
+// it. SQLiteStore and PostgresStore both run it against a real
+// database, since that's the only way to catch a backend-specific bug
+// in the schema or placeholder substitution.
+func testStoreContract(t *testing.T, newStore func() tdbank.Store) {
+	t.Helper()
+
+	store := newStore()
+	account := "checking-1234"
+
+	day := func(n int) time.Time {
+		return time.Date(2024, time.January, n, 0, 0, 0, 0, time.UTC)
+	}
+
+	records := []tdbank.HistoryRecord{
+		{
+			Index: 1, Date: day(1), Type: "DEBIT", Description: "Coffee",
+			Debit: 500, Balance: 9500,
+		},
+		{
+			Index: 1, Date: day(2), Type: "CREDIT", Description: "Paycheck",
+			Credit: 200000, Balance: 209500,
+			Extra: map[string]string{"Category": "Income", "Rewards": "0"},
+		},
+	}
+
+	added, skipped, err := store.Upsert(account, records)
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if added != 2 || skipped != 0 {
+		t.Fatalf("Upsert (first call) = added %d, skipped %d, want 2, 0", added, skipped)
+	}
+
+	// Re-upserting the same records, alongside one genuinely new one,
+	// must skip the duplicates and add only the new record.
+	newRecord := tdbank.HistoryRecord{
+		Index: 1, Date: day(3), Type: "DEBIT", Description: "Groceries",
+		Debit: 4200, Balance: 205300,
+	}
+	added, skipped, err = store.Upsert(account, append(append([]tdbank.HistoryRecord{}, records...), newRecord))
+	if err != nil {
+		t.Fatalf("Upsert (second call): %v", err)
+	}
+	if added != 1 || skipped != 2 {
+		t.Fatalf("Upsert (second call) = added %d, skipped %d, want 1, 2", added, skipped)
+	}
+
+	latest, err := store.Latest(account)
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if !latest.Equal(day(3)) {
+		t.Errorf("Latest = %v, want %v", latest, day(3))
+	}
+
+	if empty, err := store.Latest("no-such-account"); err != nil || !empty.IsZero() {
+		t.Errorf("Latest for unknown account = %v, %v, want zero time, nil", empty, err)
+	}
+
+	got, err := store.Range(account, day(2), day(3))
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Range(day2, day3) returned %d records, want 2", len(got))
+	}
+	if got[0].Description != "Paycheck" || got[1].Description != "Groceries" {
+		t.Errorf("Range returned %q, %q; want Paycheck, Groceries", got[0].Description, got[1].Description)
+	}
+
+	if extra := got[0].Extra; extra["Category"] != "Income" || extra["Rewards"] != "0" {
+		t.Errorf("Range didn't round-trip Extra: got %#v", extra)
+	}
+	if got[1].Extra != nil {
+		t.Errorf("Range returned non-nil Extra for a record with none: %#v", got[1].Extra)
+	}
+}